@@ -806,6 +806,8 @@ type hashJoinTestCase struct {
 	rows               int
 	cols               []*types.FieldType
 	concurrency        int
+	buildConcurrency   int
+	probeConcurrency   int
 	ctx                sessionctx.Context
 	keyIdx             []int
 	joinType           core.JoinType
@@ -877,7 +879,8 @@ func prepare4HashJoin(testCase *hashJoinTestCase, innerExec, outerExec Executor)
 	}
 	e := &HashJoinExec{
 		baseExecutor:      newBaseExecutor(testCase.ctx, joinSchema, 5, innerExec, outerExec),
-		concurrency:       uint(testCase.concurrency),
+		probeConcurrency:  uint(testCase.concurrency),
+		buildConcurrency:  uint(testCase.concurrency),
 		joinType:          testCase.joinType, // 0 for InnerJoin, 1 for LeftOutersJoin, 2 for RightOuterJoin
 		isOuterJoin:       false,
 		buildKeys:         joinKeys,
@@ -891,8 +894,14 @@ func prepare4HashJoin(testCase *hashJoinTestCase, innerExec, outerExec Executor)
 	childrenUsedSchema := markChildrenUsedCols(e.Schema(), e.children[0].Schema(), e.children[1].Schema())
 	defaultValues := make([]types.Datum, e.buildSideExec.Schema().Len())
 	lhsTypes, rhsTypes := retTypes(innerExec), retTypes(outerExec)
-	e.joiners = make([]joiner, e.concurrency)
-	for i := uint(0); i < e.concurrency; i++ {
+	if testCase.probeConcurrency > 0 {
+		e.probeConcurrency = uint(testCase.probeConcurrency)
+	}
+	if testCase.buildConcurrency > 0 {
+		e.buildConcurrency = uint(testCase.buildConcurrency)
+	}
+	e.joiners = make([]joiner, e.joinWorkerSlots())
+	for i := range e.joiners {
 		e.joiners[i] = newJoiner(testCase.ctx, e.joinType, true, defaultValues,
 			nil, lhsTypes, rhsTypes, childrenUsedSchema)
 	}
@@ -23,6 +23,7 @@ import (
 	"sync/atomic"
 	"time"
 
+	"github.com/cznic/mathutil"
 	"github.com/pingcap/errors"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/parser/terror"
@@ -59,10 +60,21 @@ type HashJoinExec struct {
 	probeTypes        []*types.FieldType
 	buildTypes        []*types.FieldType
 
-	// concurrency is the number of partition, build and join workers.
-	concurrency   uint
-	rowContainer  *hashRowContainer
-	buildFinished chan error
+	// probeConcurrency is the number of join workers probing the hash table,
+	// each of them owns one of probeResultChs.
+	probeConcurrency uint
+	// buildConcurrency is the number of build workers. The hash table is built
+	// by a single goroutine, so the only build side worker pool is the one
+	// scanning the hash table for the unmatched rows after probing, which only
+	// runs when useOuterToBuild is true.
+	// The build workers start after all the join workers exit, and the worker
+	// with ID i reuses the joiner and joinChkResourceCh of the join worker with
+	// ID i. So joiners and joinChkResourceCh have joinWorkerSlots() elements to
+	// serve both kinds of workers.
+	buildConcurrency uint
+	rowContainer     *hashRowContainer
+	buildFinished    chan error
+	buildChkSizer    *buildSideChunkSizer
 
 	// closeCh add a lock for closing executor.
 	closeCh      chan struct{}
@@ -154,18 +166,22 @@ func (e *HashJoinExec) Close() error {
 
 // Open implements the Executor Open interface.
 func (e *HashJoinExec) Open(ctx context.Context) error {
+	// Close is called even if Open fails, so closeCh must be created first.
+	e.closeCh = make(chan struct{})
+	e.prepared = false
+	if len(e.joiners) != e.joinWorkerSlots() {
+		return errors.Errorf("hash join has %d joiners for %d worker slots", len(e.joiners), e.joinWorkerSlots())
+	}
 	if err := e.baseExecutor.Open(ctx); err != nil {
 		return err
 	}
 
-	e.prepared = false
 	e.memTracker = memory.NewTracker(e.id, -1)
 	e.memTracker.AttachTo(e.ctx.GetSessionVars().StmtCtx.MemTracker)
 
 	e.diskTracker = disk.NewTracker(e.id, -1)
 	e.diskTracker.AttachTo(e.ctx.GetSessionVars().StmtCtx.DiskTracker)
 
-	e.closeCh = make(chan struct{})
 	e.finished.Store(false)
 	e.joinWorkerWaitGroup = sync.WaitGroup{}
 
@@ -177,13 +193,29 @@ func (e *HashJoinExec) Open(ctx context.Context) error {
 	}
 	if e.runtimeStats != nil {
 		e.stats = &hashJoinRuntimeStats{
-			concurrent: cap(e.joiners),
+			concurrent: int(e.probeConcurrency),
+		}
+		if e.useOuterToBuild {
+			e.stats.buildConcurrent = int(e.buildConcurrency)
 		}
 		e.ctx.GetSessionVars().StmtCtx.RuntimeStatsColl.RegisterStats(e.id, e.stats)
 	}
 	return nil
 }
 
+// joinWorkerSlots returns the number of joiners and joinChkResourceCh, which
+// are shared by the join workers and the build workers.
+// The hash table isn't partitioned, so there is no partition count to keep in
+// step with the two worker counts: probeResultChs is indexed by the join worker
+// ID only and always has probeConcurrency elements, and every build worker
+// strides over all the chunks of the single row container. The joiners and the
+// join result chunks are the only per worker resources used by both kinds of
+// workers, so there are max(probeConcurrency, buildConcurrency) of them, and
+// Open fails if the joiners don't match.
+func (e *HashJoinExec) joinWorkerSlots() int {
+	return mathutil.Max(int(e.probeConcurrency), int(e.buildConcurrency))
+}
+
 // fetchProbeSideChunks get chunks from fetches chunks from the big table in a background goroutine
 // and sends the chunks to multiple channels which will be read by multiple join workers.
 func (e *HashJoinExec) fetchProbeSideChunks(ctx context.Context) {
@@ -260,11 +292,13 @@ func (e *HashJoinExec) wait4BuildSide() (emptyBuild bool, err error) {
 func (e *HashJoinExec) fetchBuildSideRows(ctx context.Context, chkCh chan<- *chunk.Chunk, doneCh <-chan struct{}) {
 	defer close(chkCh)
 	var err error
+	sessVars := e.ctx.GetSessionVars()
+	e.buildChkSizer = newBuildSideChunkSizer(retTypes(e.buildSideExec), sessVars.InitChunkSize, sessVars.MaxChunkSize)
 	for {
 		if e.finished.Load().(bool) {
 			return
 		}
-		chk := chunk.NewChunkWithCapacity(e.buildSideExec.base().retFieldTypes, e.ctx.GetSessionVars().MaxChunkSize)
+		chk := chunk.NewChunkWithCapacity(retTypes(e.buildSideExec), e.buildChkSizer.capacity())
 		err = Next(ctx, e.buildSideExec, chk)
 		if err != nil {
 			e.buildFinished <- errors.Trace(err)
@@ -274,6 +308,7 @@ func (e *HashJoinExec) fetchBuildSideRows(ctx context.Context, chkCh chan<- *chu
 		if chk.NumRows() == 0 {
 			return
 		}
+		e.buildChkSizer.observe(chk)
 		select {
 		case <-doneCh:
 			return
@@ -284,19 +319,118 @@ func (e *HashJoinExec) fetchBuildSideRows(ctx context.Context, chkCh chan<- *chu
 	}
 }
 
+// buildSideChunkSizer decides the capacity of the chunks used to fetch the
+// build side rows. It starts from the initial chunk size and doubles the
+// capacity after every chunk, but never lets a chunk grow beyond the data size
+// a full chunk of rows with the estimated width of the field types would take.
+// Rows wider than estimated (e.g. big VARCHAR/JSON values) therefore result in
+// smaller chunks, so the memory consumed between two tracker checkpoints stays
+// close to the estimate instead of spiking.
+// The quota comes from the type estimate rather than the observed rows, and the
+// observed rows can only shrink the chunks against it. Types without a length,
+// like JSON and some BLOBs, are estimated as 32 bytes wide, so their quota is
+// small. To keep such rows from producing lots of tiny chunks, the capacity
+// doesn't shrink below the initial chunk size unless a single row takes more
+// than the whole quota.
+type buildSideChunkSizer struct {
+	maxChunkSize int
+	// minCapacity is the lower bound of the capacity for rows smaller than the
+	// quota, i.e. the initial chunk size.
+	minCapacity int
+	// estRowWidth is the row width estimated from the field types, in the same
+	// unit as chunk.DataSize. It's used until some rows have been observed.
+	estRowWidth float64
+	// chunkMemQuota is the expected data size of a full chunk.
+	chunkMemQuota float64
+	// chunkOverhead is the upper bound of the part of chunk.DataSize that
+	// doesn't grow with the rows, i.e. the leading offset of every var-length
+	// column and the padding of every null bitmap. It's excluded from the
+	// observed bytes so that short chunks don't raise the average row width.
+	chunkOverhead int64
+
+	observedRows  int64
+	observedBytes int64
+	curCapacity   int
+}
+
+func newBuildSideChunkSizer(fieldTypes []*types.FieldType, initChunkSize, maxChunkSize int) *buildSideChunkSizer {
+	var estRowWidth float64
+	var chunkOverhead int64
+	for _, tp := range fieldTypes {
+		// One bit of null bitmap for each row.
+		estRowWidth += float64(chunk.EstimateTypeWidth(tp)) + 1.0/8
+		chunkOverhead++
+		if chunk.GetFixedLen(tp) == -1 {
+			// The offset of the var-length element.
+			estRowWidth += 8
+			chunkOverhead += 8
+		}
+	}
+	if estRowWidth <= 0 {
+		estRowWidth = 1
+	}
+	if initChunkSize <= 0 || initChunkSize > maxChunkSize {
+		initChunkSize = maxChunkSize
+	}
+	return &buildSideChunkSizer{
+		maxChunkSize:  maxChunkSize,
+		minCapacity:   initChunkSize,
+		estRowWidth:   estRowWidth,
+		chunkMemQuota: estRowWidth * float64(maxChunkSize),
+		chunkOverhead: chunkOverhead,
+		curCapacity:   initChunkSize,
+	}
+}
+
+// capacity returns the capacity of the next chunk to be fetched.
+func (s *buildSideChunkSizer) capacity() int {
+	return s.curCapacity
+}
+
+// avgRowWidth returns the average data size of the rows observed so far,
+// or the width estimated from the field types if no row has been observed.
+func (s *buildSideChunkSizer) avgRowWidth() float64 {
+	if s.observedRows == 0 {
+		return s.estRowWidth
+	}
+	return float64(s.observedBytes) / float64(s.observedRows)
+}
+
+// observe records the data size of a fetched chunk and adjusts the capacity
+// of the next chunk accordingly.
+func (s *buildSideChunkSizer) observe(chk *chunk.Chunk) {
+	if chk.NumRows() == 0 {
+		return
+	}
+	s.observedRows += int64(chk.NumRows())
+	s.observedBytes += mathutil.MaxInt64(chk.DataSize()-s.chunkOverhead, 0)
+	newCap := mathutil.Min(s.curCapacity*2, s.maxChunkSize)
+	if avg := s.avgRowWidth(); avg > 0 {
+		if limit := int(s.chunkMemQuota / avg); newCap > limit {
+			if limit < 1 {
+				// A single row takes more than the whole quota.
+				newCap = 1
+			} else {
+				newCap = mathutil.Max(limit, mathutil.Min(s.minCapacity, newCap))
+			}
+		}
+	}
+	s.curCapacity = newCap
+}
+
 func (e *HashJoinExec) initializeForProbe() {
 	// e.probeResultChs is for transmitting the chunks which store the data of
 	// probeSideExec, it'll be written by probe side worker goroutine, and read by join
 	// workers.
-	e.probeResultChs = make([]chan *chunk.Chunk, e.concurrency)
-	for i := uint(0); i < e.concurrency; i++ {
+	e.probeResultChs = make([]chan *chunk.Chunk, e.probeConcurrency)
+	for i := uint(0); i < e.probeConcurrency; i++ {
 		e.probeResultChs[i] = make(chan *chunk.Chunk, 1)
 	}
 
 	// e.probeChkResourceCh is for transmitting the used probeSideExec chunks from
 	// join workers to probeSideExec worker.
-	e.probeChkResourceCh = make(chan *probeChkResource, e.concurrency)
-	for i := uint(0); i < e.concurrency; i++ {
+	e.probeChkResourceCh = make(chan *probeChkResource, e.probeConcurrency)
+	for i := uint(0); i < e.probeConcurrency; i++ {
 		e.probeChkResourceCh <- &probeChkResource{
 			chk:  newFirstChunk(e.probeSideExec),
 			dest: e.probeResultChs[i],
@@ -305,15 +439,15 @@ func (e *HashJoinExec) initializeForProbe() {
 
 	// e.joinChkResourceCh is for transmitting the reused join result chunks
 	// from the main thread to join worker goroutines.
-	e.joinChkResourceCh = make([]chan *chunk.Chunk, e.concurrency)
-	for i := uint(0); i < e.concurrency; i++ {
+	e.joinChkResourceCh = make([]chan *chunk.Chunk, e.joinWorkerSlots())
+	for i := range e.joinChkResourceCh {
 		e.joinChkResourceCh[i] = make(chan *chunk.Chunk, 1)
 		e.joinChkResourceCh[i] <- newFirstChunk(e)
 	}
 
 	// e.joinResultCh is for transmitting the join result chunks to the main
 	// thread.
-	e.joinResultCh = make(chan *hashjoinWorkerResult, e.concurrency+1)
+	e.joinResultCh = make(chan *hashjoinWorkerResult, e.joinWorkerSlots()+1)
 }
 
 func (e *HashJoinExec) fetchAndProbeHashTable(ctx context.Context) {
@@ -329,9 +463,9 @@ func (e *HashJoinExec) fetchAndProbeHashTable(ctx context.Context) {
 		probeKeyColIdx[i] = e.probeKeys[i].Index
 	}
 
-	// Start e.concurrency join workers to probe hash table and join build side and
+	// Start e.probeConcurrency join workers to probe hash table and join build side and
 	// probe side rows.
-	for i := uint(0); i < e.concurrency; i++ {
+	for i := uint(0); i < e.probeConcurrency; i++ {
 		e.joinWorkerWaitGroup.Add(1)
 		workID := i
 		go util.WithRecovery(func() {
@@ -366,7 +500,7 @@ func (e *HashJoinExec) handleUnmatchedRowsFromHashTable(workerID uint) {
 		return
 	}
 	numChks := e.rowContainer.NumChunks()
-	for i := int(workerID); i < numChks; i += int(e.concurrency) {
+	for i := int(workerID); i < numChks; i += int(e.buildConcurrency) {
 		chk, err := e.rowContainer.GetChunk(i)
 		if err != nil {
 			// Catching the error and send it
@@ -392,6 +526,8 @@ func (e *HashJoinExec) handleUnmatchedRowsFromHashTable(workerID uint) {
 		return
 	} else if joinResult.err != nil || (joinResult.chk != nil && joinResult.chk.NumRows() > 0) {
 		e.joinResultCh <- joinResult
+	} else if joinResult.chk != nil && joinResult.chk.NumRows() == 0 {
+		e.joinChkResourceCh[workerID] <- joinResult.chk
 	}
 }
 
@@ -399,7 +535,7 @@ func (e *HashJoinExec) waitJoinWorkersAndCloseResultChan() {
 	e.joinWorkerWaitGroup.Wait()
 	if e.useOuterToBuild {
 		// Concurrently handling unmatched rows from the hash table at the tail
-		for i := uint(0); i < e.concurrency; i++ {
+		for i := uint(0); i < e.buildConcurrency; i++ {
 			var workerID = i
 			e.joinWorkerWaitGroup.Add(1)
 			go util.WithRecovery(func() { e.handleUnmatchedRowsFromHashTable(workerID) }, e.handleJoinWorkerPanic)
@@ -1105,6 +1241,7 @@ type hashJoinRuntimeStats struct {
 	fetchAndProbe          int64
 	probe                  int64
 	concurrent             int
+	buildConcurrent        int
 	maxFetchAndProbe       int64
 }
 
@@ -1153,6 +1290,11 @@ func (e *hashJoinRuntimeStats) String() string {
 		}
 		buf.WriteString("}")
 	}
+	if e.buildConcurrent > 0 {
+		buf.WriteString(", scan_unmatched:{concurrency:")
+		buf.WriteString(strconv.Itoa(e.buildConcurrent))
+		buf.WriteString("}")
+	}
 	return buf.String()
 }
 
@@ -1163,6 +1305,7 @@ func (e *hashJoinRuntimeStats) Clone() execdetails.RuntimeStats {
 		fetchAndProbe:          e.fetchAndProbe,
 		probe:                  e.probe,
 		concurrent:             e.concurrent,
+		buildConcurrent:        e.buildConcurrent,
 		maxFetchAndProbe:       e.maxFetchAndProbe,
 	}
 }
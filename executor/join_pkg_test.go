@@ -15,13 +15,18 @@ package executor
 
 import (
 	"context"
+	"strings"
 	"time"
 
+	"github.com/cznic/mathutil"
 	. "github.com/pingcap/check"
 	"github.com/pingcap/failpoint"
 	"github.com/pingcap/parser/mysql"
 	"github.com/pingcap/tidb/expression"
+	plannercore "github.com/pingcap/tidb/planner/core"
 	"github.com/pingcap/tidb/types"
+	"github.com/pingcap/tidb/types/json"
+	"github.com/pingcap/tidb/util/chunk"
 )
 
 func (s *pkgTestSerialSuite) TestJoinExec(c *C) {
@@ -72,6 +77,9 @@ func (s *pkgTestSerialSuite) TestJoinExec(c *C) {
 				result.Append(chk, 0, chk.NumRows())
 			}
 			c.Assert(exec.rowContainer.alreadySpilledSafeForTest(), Equals, casTest.disk)
+			if !casTest.disk {
+				checkBuildSideMemoryTracked(c, exec)
+			}
 			err = exec.Close()
 			c.Assert(err, IsNil)
 		}
@@ -106,6 +114,273 @@ func (s *pkgTestSerialSuite) TestJoinExec(c *C) {
 	}
 }
 
+// checkBuildSideMemoryTracked checks that the memory tracked for the build side
+// is exactly the memory usage of the chunks put into the row container.
+func checkBuildSideMemoryTracked(c *C, exec *HashJoinExec) {
+	expected := int64(0)
+	for i := 0; i < exec.rowContainer.NumChunks(); i++ {
+		chk, err := exec.rowContainer.GetChunk(i)
+		c.Assert(err, IsNil)
+		expected += chk.MemoryUsage()
+	}
+	c.Assert(exec.rowContainer.GetMemTracker().BytesConsumed(), Equals, expected)
+}
+
+func (s *pkgTestSerialSuite) TestHashJoinExecWithAsymmetricConcurrency(c *C) {
+	colTypes := []*types.FieldType{
+		types.NewFieldType(mysql.TypeLonglong),
+		types.NewFieldType(mysql.TypeDouble),
+	}
+	runTest := func(casTest *hashJoinTestCase, expectedRows int) {
+		opt1 := mockDataSourceParameters{
+			rows: casTest.rows / 2,
+			ctx:  casTest.ctx,
+			genDataFunc: func(row int, typ *types.FieldType) interface{} {
+				switch typ.Tp {
+				case mysql.TypeLong, mysql.TypeLonglong:
+					return int64(row)
+				case mysql.TypeDouble:
+					return float64(row)
+				default:
+					panic("not implement")
+				}
+			},
+		}
+		opt2 := opt1
+		opt2.rows = casTest.rows
+		opt1.schema = expression.NewSchema(casTest.columns()...)
+		opt2.schema = expression.NewSchema(casTest.columns()...)
+		dataSource1 := buildMockDataSource(opt1)
+		dataSource2 := buildMockDataSource(opt2)
+		dataSource1.prepareChunks()
+		dataSource2.prepareChunks()
+
+		exec := prepare4HashJoin(casTest, dataSource1, dataSource2)
+		ctx := context.Background()
+		c.Assert(exec.Open(ctx), IsNil)
+		c.Assert(exec.buildConcurrency, Equals, uint(casTest.buildConcurrency))
+		c.Assert(exec.probeConcurrency, Equals, uint(casTest.probeConcurrency))
+		chk := newFirstChunk(exec)
+		numRows := 0
+		for {
+			c.Assert(exec.Next(ctx, chk), IsNil)
+			if chk.NumRows() == 0 {
+				break
+			}
+			numRows += chk.NumRows()
+		}
+		c.Assert(numRows, Equals, expectedRows)
+		checkBuildSideMemoryTracked(c, exec)
+		// Every join worker has given its probe side chunk back, and only the
+		// one taken by the probe side fetcher for the final empty read is missing.
+		c.Assert(cap(exec.probeChkResourceCh), Equals, casTest.probeConcurrency)
+		c.Assert(len(exec.probeChkResourceCh), Equals, casTest.probeConcurrency-1)
+		// Every join result chunk has been given back to its worker slot.
+		slots := mathutil.Max(casTest.probeConcurrency, casTest.buildConcurrency)
+		c.Assert(exec.joinChkResourceCh, HasLen, slots)
+		for i := range exec.joinChkResourceCh {
+			c.Assert(exec.joinChkResourceCh[i], HasLen, 1)
+		}
+		c.Assert(exec.Close(), IsNil)
+	}
+
+	// The build side has rows/2 rows and the probe side has rows rows.
+	casTest := defaultHashJoinTestCase(colTypes, plannercore.InnerJoin, false)
+	casTest.rows = 4096
+	casTest.buildConcurrency, casTest.probeConcurrency = 2, 8
+	runTest(casTest, casTest.rows/2)
+
+	// The outer side is used to build, half of its rows are unmatched and are
+	// found by the build workers scanning the hash table after probing.
+	casTest = defaultHashJoinTestCase(colTypes, plannercore.RightOuterJoin, true)
+	casTest.rows = 4096
+	casTest.buildConcurrency, casTest.probeConcurrency = 2, 8
+	runTest(casTest, casTest.rows)
+
+	// There are more build workers than join workers, the extra ones get their
+	// own joiners and join result chunks.
+	casTest = defaultHashJoinTestCase(colTypes, plannercore.RightOuterJoin, true)
+	casTest.rows = 4096
+	casTest.buildConcurrency, casTest.probeConcurrency = 8, 2
+	runTest(casTest, casTest.rows)
+
+	// Open fails if the joiners don't match the worker slots, and the executor
+	// can still be closed afterwards.
+	exec := prepare4HashJoin(casTest, buildMockDataSource(mockDataSourceParameters{
+		schema: expression.NewSchema(casTest.columns()...), ctx: casTest.ctx,
+	}), buildMockDataSource(mockDataSourceParameters{
+		schema: expression.NewSchema(casTest.columns()...), ctx: casTest.ctx,
+	}))
+	c.Assert(exec.joiners, HasLen, 8)
+	exec.joiners = exec.joiners[:2]
+	c.Assert(exec.Open(context.Background()), NotNil)
+	c.Assert(exec.Close(), IsNil)
+}
+
+func (s *pkgTestSuite) TestBuildSideChunkSizer(c *C) {
+	fillChunk := func(sizer *buildSideChunkSizer, fieldTypes []*types.FieldType, numRows int, appendRow func(chk *chunk.Chunk)) {
+		chk := chunk.NewChunkWithCapacity(fieldTypes, sizer.capacity())
+		for i := 0; i < numRows; i++ {
+			appendRow(chk)
+		}
+		sizer.observe(chk)
+	}
+	appendInts := func(chk *chunk.Chunk) {
+		for i := 0; i < chk.NumCols(); i++ {
+			chk.AppendInt64(i, int64(chk.NumRows()))
+		}
+	}
+
+	// Skinny rows: the capacity doubles until it reaches the max chunk size.
+	intTypes := []*types.FieldType{types.NewFieldType(mysql.TypeLonglong)}
+	sizer := newBuildSideChunkSizer(intTypes, 32, 1024)
+	expected := []int{32, 64, 128, 256, 512, 1024, 1024}
+	for _, capacity := range expected {
+		c.Assert(sizer.capacity(), Equals, capacity)
+		fillChunk(sizer, intTypes, sizer.capacity(), appendInts)
+	}
+
+	// Empty chunks don't change the capacity.
+	sizer.observe(chunk.NewChunkWithCapacity(intTypes, 1))
+	c.Assert(sizer.capacity(), Equals, 1024)
+
+	// Short chunks don't raise the average row width.
+	fillChunk(sizer, intTypes, 1, appendInts)
+	c.Assert(sizer.capacity(), Equals, 1024)
+	sizer = newBuildSideChunkSizer(intTypes, 32, 1024)
+	fillChunk(sizer, intTypes, 1, appendInts)
+	c.Assert(sizer.avgRowWidth() <= sizer.estRowWidth, IsTrue)
+	for _, capacity := range []int{64, 128, 256, 512, 1024} {
+		c.Assert(sizer.capacity(), Equals, capacity)
+		fillChunk(sizer, intTypes, sizer.capacity(), appendInts)
+	}
+	c.Assert(sizer.capacity(), Equals, 1024)
+
+	// Rows with exactly the estimated width reach the max chunk size.
+	wideIntTypes := make([]*types.FieldType, 64)
+	for i := range wideIntTypes {
+		wideIntTypes[i] = types.NewFieldType(mysql.TypeLonglong)
+	}
+	sizer = newBuildSideChunkSizer(wideIntTypes, 32, 1024)
+	for _, capacity := range expected {
+		c.Assert(sizer.capacity(), Equals, capacity)
+		fillChunk(sizer, wideIntTypes, sizer.capacity(), appendInts)
+	}
+	strType := types.NewFieldType(mysql.TypeVarchar)
+	strType.Flen = 255
+	strTypes := []*types.FieldType{strType}
+	estVal := strings.Repeat("a", chunk.EstimateTypeWidth(strType))
+	appendEstVal := func(chk *chunk.Chunk) { chk.AppendString(0, estVal) }
+	sizer = newBuildSideChunkSizer(strTypes, 32, 1024)
+	for _, capacity := range expected {
+		c.Assert(sizer.capacity(), Equals, capacity)
+		fillChunk(sizer, strTypes, sizer.capacity(), appendEstVal)
+	}
+
+	// Wide rows: the capacity stops growing once a chunk takes about the data
+	// size estimated from the field types.
+	strType = types.NewFieldType(mysql.TypeVarchar)
+	strType.Flen = 10
+	strTypes = []*types.FieldType{strType}
+	sizer = newBuildSideChunkSizer(strTypes, 32, 1024)
+	quota := sizer.chunkMemQuota
+	wideVal := strings.Repeat("a", 100)
+	appendWideVal := func(chk *chunk.Chunk) { chk.AppendString(0, wideVal) }
+	for _, capacity := range []int{32, 64, 128} {
+		c.Assert(sizer.capacity(), Equals, capacity)
+		fillChunk(sizer, strTypes, sizer.capacity(), appendWideVal)
+	}
+	limit := sizer.capacity()
+	c.Assert(limit > 128 && limit < 1024, IsTrue)
+	c.Assert(limit, Equals, int(quota/sizer.avgRowWidth()))
+	for i := 0; i < 3; i++ {
+		fillChunk(sizer, strTypes, sizer.capacity(), appendWideVal)
+		c.Assert(sizer.capacity(), Equals, limit)
+	}
+
+	// Rows that would fit less than the initial chunk size into the quota, like
+	// big JSON documents estimated as 32 bytes wide, keep the initial chunk size.
+	jsonTypes := []*types.FieldType{types.NewFieldType(mysql.TypeJSON)}
+	sizer = newBuildSideChunkSizer(jsonTypes, 32, 1024)
+	doc := json.CreateBinary(strings.Repeat("a", 2048))
+	appendDoc := func(chk *chunk.Chunk) { chk.AppendJSON(0, doc) }
+	for i := 0; i < 5; i++ {
+		fillChunk(sizer, jsonTypes, sizer.capacity(), appendDoc)
+		c.Assert(float64(32)*sizer.avgRowWidth() > sizer.chunkMemQuota, IsTrue)
+		c.Assert(sizer.capacity(), Equals, 32)
+	}
+
+	// Rows wider than the whole quota get a chunk of one row.
+	sizer = newBuildSideChunkSizer(strTypes, 32, 1024)
+	hugeVal := strings.Repeat("a", int(quota)+1)
+	fillChunk(sizer, strTypes, 1, func(chk *chunk.Chunk) { chk.AppendString(0, hugeVal) })
+	c.Assert(sizer.capacity(), Equals, 1)
+}
+
+// capacityRecorder records the capacity of the chunks its child is asked to fill.
+type capacityRecorder struct {
+	*mockDataSource
+	capacities []int
+}
+
+func (r *capacityRecorder) Next(ctx context.Context, req *chunk.Chunk) error {
+	r.capacities = append(r.capacities, req.Capacity())
+	return r.mockDataSource.Next(ctx, req)
+}
+
+func (s *pkgTestSuite) TestHashJoinBuildSideChunkCapacity(c *C) {
+	colTypes := []*types.FieldType{
+		types.NewFieldType(mysql.TypeLonglong),
+		types.NewFieldType(mysql.TypeDouble),
+	}
+	casTest := defaultHashJoinTestCase(colTypes, plannercore.InnerJoin, false)
+	casTest.rows = 4096
+	opt := mockDataSourceParameters{
+		rows: casTest.rows,
+		ctx:  casTest.ctx,
+		genDataFunc: func(row int, typ *types.FieldType) interface{} {
+			switch typ.Tp {
+			case mysql.TypeLong, mysql.TypeLonglong:
+				return int64(row)
+			case mysql.TypeDouble:
+				return float64(row)
+			default:
+				panic("not implement")
+			}
+		},
+	}
+	opt.schema = expression.NewSchema(casTest.columns()...)
+	buildSide := &capacityRecorder{mockDataSource: buildMockDataSource(opt)}
+	opt.schema = expression.NewSchema(casTest.columns()...)
+	probeSide := buildMockDataSource(opt)
+	buildSide.prepareChunks()
+	probeSide.prepareChunks()
+
+	exec := prepare4HashJoin(casTest, buildSide, probeSide)
+	ctx := context.Background()
+	c.Assert(exec.Open(ctx), IsNil)
+	chk := newFirstChunk(exec)
+	numRows := 0
+	for {
+		c.Assert(exec.Next(ctx, chk), IsNil)
+		if chk.NumRows() == 0 {
+			break
+		}
+		numRows += chk.NumRows()
+	}
+	c.Assert(numRows, Equals, casTest.rows)
+	c.Assert(exec.Close(), IsNil)
+
+	// The build side fetcher has exited after Close, so the sizer is safe to
+	// read. The mock data source always returns full chunks of MaxChunkSize
+	// rows, so the capacity doubles after every chunk, and the empty final read
+	// was asked with the capacity the sizer ended with.
+	sessVars := casTest.ctx.GetSessionVars()
+	c.Assert(buildSide.capacities, DeepEquals, []int{32, 64, 128, 256, 512})
+	c.Assert(buildSide.capacities[0], Equals, sessVars.InitChunkSize)
+	c.Assert(exec.buildChkSizer.capacity(), Equals, buildSide.capacities[len(buildSide.capacities)-1])
+}
+
 func (s *pkgTestSuite) TestHashJoinRuntimeStats(c *C) {
 	stats := &hashJoinRuntimeStats{
 		fetchAndBuildHashTable: 2 * time.Second,
@@ -122,6 +397,9 @@ func (s *pkgTestSuite) TestHashJoinRuntimeStats(c *C) {
 	c.Assert(stats.String(), Equals, stats.Clone().String())
 	stats.Merge(stats.Clone())
 	c.Assert(stats.String(), Equals, "build_hash_table:{total:4s, fetch:3.8s, build:200ms}, probe:{concurrency:4, total:10s, max:2s, probe:8s, fetch:2s, probe_collision:2}")
+	stats.buildConcurrent = 2
+	c.Assert(stats.String(), Equals, "build_hash_table:{total:4s, fetch:3.8s, build:200ms}, probe:{concurrency:4, total:10s, max:2s, probe:8s, fetch:2s, probe_collision:2}, scan_unmatched:{concurrency:2}")
+	c.Assert(stats.String(), Equals, stats.Clone().String())
 }
 
 func (s *pkgTestSuite) TestIndexJoinRuntimeStats(c *C) {
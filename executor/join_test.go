@@ -1627,6 +1627,39 @@ func (s *testSuiteJoin1) TestHashJoin(c *C) {
 	c.Assert(outerActRows, Equals, "5")
 }
 
+func (s *testSuiteJoinSerial) TestHashJoinWithAsymmetricConcurrency(c *C) {
+	plannercore.ForceUseOuterBuild4Test = true
+	defer func() { plannercore.ForceUseOuterBuild4Test = false }()
+	tk := testkit.NewTestKit(c, s.store)
+	tk.MustExec("use test")
+	tk.MustExec("drop table if exists t1, t2")
+	tk.MustExec("create table t1(a int, b int);")
+	tk.MustExec("create table t2(a int, b int);")
+	for i := 1; i <= 100; i++ {
+		tk.MustExec(fmt.Sprintf("insert into t1 values(%d, %d), (%d, %d);", i, i, i, i))
+		tk.MustExec(fmt.Sprintf("insert into t2 values(%d, %d);", 2*i, 2*i))
+	}
+	tk.MustExec("set @@tidb_init_chunk_size=1;")
+	tk.MustExec("set @@tidb_max_chunk_size=32;")
+	for _, concurrency := range [][2]int{{2, 8}, {8, 2}} {
+		tk.MustExec(fmt.Sprintf("set @@tidb_hash_join_build_concurrency=%d;", concurrency[0]))
+		tk.MustExec(fmt.Sprintf("set @@tidb_hash_join_probe_concurrency=%d;", concurrency[1]))
+		rows := tk.MustQuery("explain analyze select /*+ TIDB_HJ(t1, t2) */ * from t1 left join t2 on t1.a = t2.a").Rows()
+		// The outer side t1 builds the hash table, so the unmatched rows are
+		// found by the build workers scanning the hash table after probing.
+		c.Assert(rows[1][0], Matches, ".*\\(Build\\)")
+		c.Assert(rows[2][4], Equals, "table:t1")
+		c.Assert(rows[0][5], Matches, fmt.Sprintf(".*probe:{concurrency:%d,.*", concurrency[1]))
+		c.Assert(rows[0][5], Matches, fmt.Sprintf(".*scan_unmatched:{concurrency:%d}.*", concurrency[0]))
+		for i := 0; i < 5; i++ {
+			tk.MustQuery("select /*+ TIDB_HJ(t1, t2) */ count(*), count(t2.a), sum(t1.a) from t1 left join t2 on t1.a = t2.a").Check(testkit.Rows(
+				"200 100 10100"))
+			tk.MustQuery("select /*+ TIDB_HJ(t1, t2) */ count(*), count(t1.a), sum(t2.a) from t1 right join t2 on t1.a = t2.a").Check(testkit.Rows(
+				"150 100 12650"))
+		}
+	}
+}
+
 func (s *testSuiteJoin1) TestJoinDifferentDecimals(c *C) {
 	tk := testkit.NewTestKit(c, s.store)
 	tk.MustExec("Use test")
@@ -1094,6 +1094,8 @@ func (s *testSuite5) TestSetConcurrency(c *C) {
 	tk.MustQuery("select @@tidb_index_lookup_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
 	tk.MustQuery("select @@tidb_index_lookup_join_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
 	tk.MustQuery("select @@tidb_hash_join_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
+	tk.MustQuery("select @@tidb_hash_join_build_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
+	tk.MustQuery("select @@tidb_hash_join_probe_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
 	tk.MustQuery("select @@tidb_hashagg_partial_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
 	tk.MustQuery("select @@tidb_hashagg_final_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
 	tk.MustQuery("select @@tidb_window_concurrency;").Check(testkit.Rows(strconv.Itoa(variable.ConcurrencyUnset)))
@@ -1108,6 +1110,8 @@ func (s *testSuite5) TestSetConcurrency(c *C) {
 	c.Assert(vars.IndexLookupConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.IndexLookupJoinConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.HashJoinConcurrency(), Equals, variable.DefExecutorConcurrency)
+	c.Assert(vars.HashJoinBuildConcurrency(), Equals, variable.DefExecutorConcurrency)
+	c.Assert(vars.HashJoinProbeConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.HashAggPartialConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.HashAggFinalConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.WindowConcurrency(), Equals, variable.DefExecutorConcurrency)
@@ -1134,6 +1138,15 @@ func (s *testSuite5) TestSetConcurrency(c *C) {
 
 	checkSet(variable.TiDBHashJoinConcurrency)
 	c.Assert(vars.HashJoinConcurrency(), Equals, 1)
+	c.Assert(vars.HashJoinBuildConcurrency(), Equals, 1)
+	c.Assert(vars.HashJoinProbeConcurrency(), Equals, 1)
+
+	tk.MustExec("set @@tidb_hash_join_build_concurrency=2;")
+	tk.MustExec("set @@tidb_hash_join_probe_concurrency=8;")
+	tk.MustQuery("show warnings").Check(testkit.Rows())
+	tk.MustQuery("select @@tidb_hash_join_build_concurrency, @@tidb_hash_join_probe_concurrency;").Check(testkit.Rows("2 8"))
+	c.Assert(vars.HashJoinBuildConcurrency(), Equals, 2)
+	c.Assert(vars.HashJoinProbeConcurrency(), Equals, 8)
 
 	checkSet(variable.TiDBHashAggPartialConcurrency)
 	c.Assert(vars.HashAggPartialConcurrency(), Equals, 1)
@@ -1163,6 +1176,8 @@ func (s *testSuite5) TestSetConcurrency(c *C) {
 	tk.MustExec("set @@tidb_index_lookup_concurrency=-1;")
 	tk.MustExec("set @@tidb_index_lookup_join_concurrency=-1;")
 	tk.MustExec("set @@tidb_hash_join_concurrency=-1;")
+	tk.MustExec("set @@tidb_hash_join_build_concurrency=-1;")
+	tk.MustExec("set @@tidb_hash_join_probe_concurrency=-1;")
 	tk.MustExec("set @@tidb_hashagg_partial_concurrency=-1;")
 	tk.MustExec("set @@tidb_hashagg_final_concurrency=-1;")
 	tk.MustExec("set @@tidb_window_concurrency=-1;")
@@ -1172,6 +1187,8 @@ func (s *testSuite5) TestSetConcurrency(c *C) {
 	c.Assert(vars.IndexLookupConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.IndexLookupJoinConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.HashJoinConcurrency(), Equals, variable.DefExecutorConcurrency)
+	c.Assert(vars.HashJoinBuildConcurrency(), Equals, variable.DefExecutorConcurrency)
+	c.Assert(vars.HashJoinProbeConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.HashAggPartialConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.HashAggFinalConcurrency(), Equals, variable.DefExecutorConcurrency)
 	c.Assert(vars.WindowConcurrency(), Equals, variable.DefExecutorConcurrency)
@@ -718,6 +718,14 @@ type PhysicalHashJoin struct {
 	Concurrency     uint
 	EqualConditions []*expression.ScalarFunction
 
+	// ProbeConcurrency and BuildConcurrency override Concurrency for the join
+	// workers and the build workers respectively, use GetProbeConcurrency and
+	// GetBuildConcurrency to read them. The hash table is built by a single
+	// goroutine, so the build workers only scan the hash table built from the
+	// outer side for the unmatched rows.
+	ProbeConcurrency uint
+	BuildConcurrency uint
+
 	// use the outer table to build a hash table when the outer table is smaller.
 	UseOuterToBuild bool
 
@@ -726,6 +734,23 @@ type PhysicalHashJoin struct {
 	globalChildIndex int
 }
 
+// GetProbeConcurrency returns the number of join workers probing the hash table.
+func (p *PhysicalHashJoin) GetProbeConcurrency() uint {
+	if p.ProbeConcurrency > 0 {
+		return p.ProbeConcurrency
+	}
+	return p.Concurrency
+}
+
+// GetBuildConcurrency returns the number of build workers, which scan the hash
+// table for the unmatched rows when it's built from the outer side.
+func (p *PhysicalHashJoin) GetBuildConcurrency() uint {
+	if p.BuildConcurrency > 0 {
+		return p.BuildConcurrency
+	}
+	return p.Concurrency
+}
+
 // Clone implements PhysicalPlan interface.
 func (p *PhysicalHashJoin) Clone() (PhysicalPlan, error) {
 	cloned := new(PhysicalHashJoin)
@@ -735,6 +760,8 @@ func (p *PhysicalHashJoin) Clone() (PhysicalPlan, error) {
 	}
 	cloned.basePhysicalJoin = *base
 	cloned.Concurrency = p.Concurrency
+	cloned.ProbeConcurrency = p.ProbeConcurrency
+	cloned.BuildConcurrency = p.BuildConcurrency
 	cloned.UseOuterToBuild = p.UseOuterToBuild
 	for _, c := range p.EqualConditions {
 		cloned.EqualConditions = append(cloned.EqualConditions, c.Clone().(*expression.ScalarFunction))
@@ -778,6 +805,8 @@ func NewPhysicalHashJoin(p *LogicalJoin, innerIdx int, useOuterToBuild bool, new
 		basePhysicalJoin: baseJoin,
 		EqualConditions:  p.EqualConditions,
 		Concurrency:      uint(p.ctx.GetSessionVars().HashJoinConcurrency()),
+		ProbeConcurrency: uint(p.ctx.GetSessionVars().HashJoinProbeConcurrency()),
+		BuildConcurrency: uint(p.ctx.GetSessionVars().HashJoinBuildConcurrency()),
 		UseOuterToBuild:  useOuterToBuild,
 	}.Init(p.ctx, newStats, p.blockOffset, prop...)
 	return hashJoin
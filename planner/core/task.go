@@ -521,16 +521,17 @@ func (p *PhysicalHashJoin) GetCost(lCnt, rCnt float64) float64 {
 		probeCost += probeCnt * sessVars.CPUFactor
 	}
 	diskCost += probeDiskCost
-	probeCost /= float64(p.Concurrency)
+	probeConcurrency := p.GetProbeConcurrency()
+	probeCost /= float64(probeConcurrency)
 	// Cost of additional concurrent goroutines.
-	cpuCost += probeCost + float64(p.Concurrency+1)*sessVars.ConcurrencyFactor
+	cpuCost += probeCost + float64(probeConcurrency+1)*sessVars.ConcurrencyFactor
 	// Cost of traveling the hash table to resolve missing matched cases when building the hash table from the outer table
 	if p.UseOuterToBuild {
 		if spill {
 			// It runs in sequence when build data is on disk. See handleUnmatchedRowsFromHashTableInDisk
 			cpuCost += buildCnt * sessVars.CPUFactor
 		} else {
-			cpuCost += buildCnt * sessVars.CPUFactor / float64(p.Concurrency)
+			cpuCost += buildCnt * sessVars.CPUFactor / float64(p.GetBuildConcurrency())
 		}
 		diskCost += buildCnt * sessVars.DiskFactor * rowSize
 	}
@@ -2529,6 +2529,8 @@ var builtinGlobalVariable = []string{
 	variable.TiDBIndexLookupJoinConcurrency,
 	variable.TiDBIndexSerialScanConcurrency,
 	variable.TiDBHashJoinConcurrency,
+	variable.TiDBHashJoinBuildConcurrency,
+	variable.TiDBHashJoinProbeConcurrency,
 	variable.TiDBProjectionConcurrency,
 	variable.TiDBHashAggPartialConcurrency,
 	variable.TiDBHashAggFinalConcurrency,
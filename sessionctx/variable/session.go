@@ -1005,6 +1005,8 @@ func NewSessionVars() *SessionVars {
 		indexSerialScanConcurrency: DefIndexSerialScanConcurrency,
 		indexLookupJoinConcurrency: DefIndexLookupJoinConcurrency,
 		hashJoinConcurrency:        DefTiDBHashJoinConcurrency,
+		hashJoinBuildConcurrency:   DefTiDBHashJoinBuildConcurrency,
+		hashJoinProbeConcurrency:   DefTiDBHashJoinProbeConcurrency,
 		projectionConcurrency:      DefTiDBProjectionConcurrency,
 		distSQLScanConcurrency:     DefDistSQLScanConcurrency,
 		hashAggPartialConcurrency:  DefTiDBHashAggPartialConcurrency,
@@ -1469,6 +1471,10 @@ func (s *SessionVars) SetSystemVar(name string, val string) error {
 		s.IndexLookupSize = tidbOptPositiveInt32(val, DefIndexLookupSize)
 	case TiDBHashJoinConcurrency:
 		s.hashJoinConcurrency = tidbOptPositiveInt32(val, ConcurrencyUnset)
+	case TiDBHashJoinBuildConcurrency:
+		s.hashJoinBuildConcurrency = tidbOptPositiveInt32(val, ConcurrencyUnset)
+	case TiDBHashJoinProbeConcurrency:
+		s.hashJoinProbeConcurrency = tidbOptPositiveInt32(val, ConcurrencyUnset)
 	case TiDBProjectionConcurrency:
 		s.projectionConcurrency = tidbOptPositiveInt32(val, ConcurrencyUnset)
 	case TiDBHashAggPartialConcurrency:
@@ -1868,6 +1874,13 @@ type Concurrency struct {
 	// hashJoinConcurrency is deprecated, use ExecutorConcurrency instead.
 	hashJoinConcurrency int
 
+	// hashJoinBuildConcurrency is the number of concurrent hash join build worker,
+	// which only scan the hash table built from the outer side for the unmatched rows.
+	hashJoinBuildConcurrency int
+
+	// hashJoinProbeConcurrency is the number of concurrent hash join probe worker.
+	hashJoinProbeConcurrency int
+
 	// projectionConcurrency is the number of concurrent projection worker.
 	// projectionConcurrency is deprecated, use ExecutorConcurrency instead.
 	projectionConcurrency int
@@ -1921,6 +1934,16 @@ func (c *Concurrency) SetHashJoinConcurrency(n int) {
 	c.hashJoinConcurrency = n
 }
 
+// SetHashJoinBuildConcurrency set the number of concurrent hash join build worker.
+func (c *Concurrency) SetHashJoinBuildConcurrency(n int) {
+	c.hashJoinBuildConcurrency = n
+}
+
+// SetHashJoinProbeConcurrency set the number of concurrent hash join probe worker.
+func (c *Concurrency) SetHashJoinProbeConcurrency(n int) {
+	c.hashJoinProbeConcurrency = n
+}
+
 // SetProjectionConcurrency set the number of concurrent projection worker.
 func (c *Concurrency) SetProjectionConcurrency(n int) {
 	c.projectionConcurrency = n
@@ -1985,6 +2008,22 @@ func (c *Concurrency) HashJoinConcurrency() int {
 	return c.ExecutorConcurrency
 }
 
+// HashJoinBuildConcurrency return the number of concurrent hash join build worker.
+func (c *Concurrency) HashJoinBuildConcurrency() int {
+	if c.hashJoinBuildConcurrency != ConcurrencyUnset {
+		return c.hashJoinBuildConcurrency
+	}
+	return c.HashJoinConcurrency()
+}
+
+// HashJoinProbeConcurrency return the number of concurrent hash join probe worker.
+func (c *Concurrency) HashJoinProbeConcurrency() int {
+	if c.hashJoinProbeConcurrency != ConcurrencyUnset {
+		return c.hashJoinProbeConcurrency
+	}
+	return c.HashJoinConcurrency()
+}
+
 // ProjectionConcurrency return the number of concurrent projection worker.
 func (c *Concurrency) ProjectionConcurrency() int {
 	if c.projectionConcurrency != ConcurrencyUnset {
@@ -313,6 +313,20 @@ const (
 	// tidb_hash_join_concurrency is deprecated, use tidb_executor_concurrency instead.
 	TiDBHashJoinConcurrency = "tidb_hash_join_concurrency"
 
+	// tidb_hash_join_build_concurrency is used for hash join executor.
+	// It controls the number of build side workers, and falls back to
+	// tidb_hash_join_concurrency when unset. The hash table is built by a
+	// single goroutine, so the only build side workers are the ones scanning
+	// the hash table for the unmatched outer rows after probing, when an outer
+	// join builds the hash table from the outer side. It has no effect on
+	// other hash joins.
+	TiDBHashJoinBuildConcurrency = "tidb_hash_join_build_concurrency"
+
+	// tidb_hash_join_probe_concurrency is used for hash join executor.
+	// It controls the number of join workers probing the hash table,
+	// and falls back to tidb_hash_join_concurrency when unset.
+	TiDBHashJoinProbeConcurrency = "tidb_hash_join_probe_concurrency"
+
 	// tidb_projection_concurrency is used for projection operator.
 	// This variable controls the worker number of projection operator.
 	// tidb_projection_concurrency is deprecated, use tidb_executor_concurrency instead.
@@ -603,6 +617,8 @@ const (
 	DefTiDBDisableTxnAutoRetry         = true
 	DefTiDBConstraintCheckInPlace      = false
 	DefTiDBHashJoinConcurrency         = ConcurrencyUnset
+	DefTiDBHashJoinBuildConcurrency    = ConcurrencyUnset
+	DefTiDBHashJoinProbeConcurrency    = ConcurrencyUnset
 	DefTiDBProjectionConcurrency       = ConcurrencyUnset
 	DefBroadcastJoinThresholdSize      = 100 * 1024 * 1024
 	DefBroadcastJoinThresholdCount     = 10 * 1024
@@ -139,6 +139,16 @@ func (c *Chunk) MemoryUsage() (sum int64) {
 	return
 }
 
+// DataSize returns the total size in bytes of the data appended to a Chunk.
+// Unlike MemoryUsage, it counts the length instead of the capacity of the
+// null bitmap, offsets and data of each Column, and ignores the Column headers.
+func (c *Chunk) DataSize() (sum int64) {
+	for _, col := range c.columns {
+		sum += int64(len(col.nullBitmap)) + int64(len(col.offsets)*8) + int64(len(col.data))
+	}
+	return
+}
+
 // newFixedLenColumn creates a fixed length Column with elemLen and initial data capacity.
 func newFixedLenColumn(elemLen, cap int) *Column {
 	return &Column{
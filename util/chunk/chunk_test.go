@@ -604,6 +604,28 @@ func (s *testChunkSuite) TestChunkMemoryUsage(c *check.C) {
 	c.Assert(memUsage, check.Equals, int64(expectedUsage))
 }
 
+func (s *testChunkSuite) TestChunkDataSize(c *check.C) {
+	fieldTypes := make([]*types.FieldType, 0, 2)
+	fieldTypes = append(fieldTypes, &types.FieldType{Tp: mysql.TypeLonglong})
+	fieldTypes = append(fieldTypes, &types.FieldType{Tp: mysql.TypeVarchar})
+
+	chk := NewChunkWithCapacity(fieldTypes, 32)
+	// The var-length column always has the leading offset.
+	c.Assert(chk.DataSize(), check.Equals, int64(8))
+
+	for i := 0; i < 9; i++ {
+		chk.AppendInt64(0, int64(i))
+		chk.AppendString(1, "abc")
+	}
+	// len(c.nullBitmap) + len(c.offsets)*8 + len(c.data)
+	expected := (2 + 0 + 9*8) + (2 + 10*8 + 9*3)
+	c.Assert(chk.DataSize(), check.Equals, int64(expected))
+	c.Assert(chk.DataSize() < chk.MemoryUsage(), check.IsTrue)
+
+	chk.Reset()
+	c.Assert(chk.DataSize(), check.Equals, int64(8))
+}
+
 func (s *testChunkSuite) TestSwapColumn(c *check.C) {
 	fieldTypes := make([]*types.FieldType, 0, 2)
 	fieldTypes = append(fieldTypes, &types.FieldType{Tp: mysql.TypeFloat})